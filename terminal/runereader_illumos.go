@@ -1,5 +1,5 @@
-//go:build solaris && illumos
-// +build solaris,illumos
+//go:build solaris || illumos
+// +build solaris illumos
 
 package terminal
 
@@ -7,74 +7,150 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"os"
+
+	"golang.org/x/sys/unix"
 )
 
-/*
-#include <errno.h>
-#include <stdio.h>
-#include <stropts.h>
-#include <termios.h>
-#include <unistd.h>
+func getTerminalMode(fd uintptr) (*unix.Termios, error) {
+	t, err := unix.IoctlGetTermios(int(fd), unix.TCGETS)
+	if err != nil {
+		return nil, fmt.Errorf("TCGETS ioctl failed: %w", err)
+	}
+	return t, nil
+}
 
-int wrapper_set_term_mode(int fd, struct termios *t) {
-  int res = ioctl(fd, TCSETS, t);
-  if (res != 0) return errno;
+func alterTerminalMode(t *unix.Termios) {
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+}
 
-  return 0;
+func setTerminalMode(fd uintptr, t *unix.Termios) error {
+	if err := unix.IoctlSetTermios(int(fd), unix.TCSETS, t); err != nil {
+		return fmt.Errorf("TCSETS ioctl failed: %w", err)
+	}
+	return nil
 }
 
-int wrapper_tweak_term_mode(int fd, struct termios *t) {
-  t->c_lflag &= ~(ECHO | ECHONL | ICANON | ISIG);
-  t->c_cc[VMIN] = 1;
-  t->c_cc[VTIME] = 0;
+const (
+	normalKeypad      = '['
+	applicationKeypad = 'O'
+)
+
+// Mods is a bitmask of the modifier keys (Shift, Alt, Ctrl) reported by a
+// CSI sequence's modifier parameter, e.g. the `5` in ESC [ 1 ; 5 C
+// (Ctrl+Right).
+type Mods uint8
 
-  int res = ioctl(fd, TCSETS, t);
-  if (res != 0) return errno;
+const (
+	ModShift Mods = 1 << iota
+	ModAlt
+	ModCtrl
+)
 
-  return 0;
+// ModifiedKey pairs a key rune with any modifier keys that were held down
+// when it was pressed.
+type ModifiedKey struct {
+	Key  rune
+	Mods Mods
 }
 
-int wrapper_get_term_mode(int fd, struct termios *t) {
-  int res = ioctl(fd, TCGETS, t);
-  if (res != 0) return errno;
+// Additional special keys decoded from `ESC [ n ~` sequences, continuing on
+// from the existing SpecialKeyHome/End/Delete constants. Values live in the
+// Unicode private-use area to stay clear of ordinary input runes.
+const (
+	SpecialKeyInsert rune = 0xE000 + iota
+	SpecialKeyPageUp
+	SpecialKeyPageDown
+)
 
-  return 0;
-}
-*/
-import "C"
+// KeyF1..KeyF12 are the function keys, decoded from `ESC O P`..`ESC O S`
+// (SS3) or `ESC [ 11~`..`ESC [ 24~` (CSI).
+const (
+	KeyF1 rune = 0xE010 + iota
+	KeyF2
+	KeyF3
+	KeyF4
+	KeyF5
+	KeyF6
+	KeyF7
+	KeyF8
+	KeyF9
+	KeyF10
+	KeyF11
+	KeyF12
+)
 
-func getTerminalMode(fd uintptr, t *C.struct_termios) error {
-	res := C.wrapper_get_term_mode(C.int(fd), t)
-	if res != 0 {
-		return fmt.Errorf("TCGETS ioctl failed with error code: %d", res)
-	}
-	return nil
+// ss3Keys maps the SS3 (`ESC O x`) final byte to a key.
+var ss3Keys = map[rune]rune{
+	'A': KeyArrowUp,
+	'B': KeyArrowDown,
+	'C': KeyArrowRight,
+	'D': KeyArrowLeft,
+	'F': SpecialKeyEnd,
+	'H': SpecialKeyHome,
+	'P': KeyF1,
+	'Q': KeyF2,
+	'R': KeyF3,
+	'S': KeyF4,
 }
 
-func alterTerminalMode(fd uintptr, t *C.struct_termios) error {
-	if res := C.wrapper_tweak_term_mode(C.int(fd), t); res != 0 {
-		return fmt.Errorf("TCSETS ioctl failed with error code: %d", res)
-	}
-	return nil
+// csiLetterKeys maps the final byte of a CSI sequence with no `~` (e.g.
+// `ESC [ A` or `ESC [ 1 ; 5 C`) to a key.
+var csiLetterKeys = map[rune]rune{
+	'A': KeyArrowUp,
+	'B': KeyArrowDown,
+	'C': KeyArrowRight,
+	'D': KeyArrowLeft,
+	'F': SpecialKeyEnd,
+	'H': SpecialKeyHome,
 }
 
-func setTerminalMode(fd uintptr, t *C.struct_termios) error {
-	if res := C.wrapper_set_term_mode(C.int(fd), t); res != 0 {
-		return fmt.Errorf("TCSETS ioctl failed with error code: %d", res)
-	}
-	return nil
+// tildeKeys maps the leading numeric parameter of a `ESC [ n ~` sequence to
+// a key, following the common xterm numbering (note 16 and 22 are skipped).
+var tildeKeys = map[int]rune{
+	1:  SpecialKeyHome,
+	2:  SpecialKeyInsert,
+	3:  SpecialKeyDelete,
+	4:  SpecialKeyEnd,
+	5:  SpecialKeyPageUp,
+	6:  SpecialKeyPageDown,
+	11: KeyF1,
+	12: KeyF2,
+	13: KeyF3,
+	14: KeyF4,
+	15: KeyF5,
+	17: KeyF6,
+	18: KeyF7,
+	19: KeyF8,
+	20: KeyF9,
+	21: KeyF10,
+	23: KeyF11,
+	24: KeyF12,
+
+	// Bracketed paste framing, ESC [ 200 ~ ... ESC [ 201 ~.
+	200: KeyPasteStart,
+	201: KeyPasteEnd,
 }
 
+// KeyPasteStart and KeyPasteEnd are sentinel keys returned by ReadRune to
+// mark the boundaries of a bracketed paste block. On KeyPasteStart, call
+// ReadPaste to consume the pasted text up to the matching KeyPasteEnd.
 const (
-	normalKeypad      = '['
-	applicationKeypad = 'O'
+	KeyPasteStart rune = 0xE0F0
+	KeyPasteEnd   rune = 0xE0F1
 )
 
 type runeReaderState struct {
-	term   C.struct_termios
-	reader *bufio.Reader
-	buf    *bytes.Buffer
+	term           unix.Termios
+	reader         *bufio.Reader
+	buf            *bytes.Buffer
+	key            ModifiedKey
+	bracketedPaste bool
+	inputFilter    func(rune) (rune, bool)
+	out            io.Writer // where mode-toggle escape sequences are written; nil means os.Stdout
 }
 
 func newRuneReaderState(input FileReader) runeReaderState {
@@ -92,52 +168,208 @@ func (rr *RuneReader) Buffer() *bytes.Buffer {
 	return rr.state.buf
 }
 
+// SetBracketedPasteMode opts in (or out) of bracketed paste mode. When
+// enabled, SetTermMode and RestoreTermMode toggle the terminal's bracketed
+// paste mode alongside raw mode, so the terminal frames pasted text between
+// KeyPasteStart/KeyPasteEnd. ReadRune still returns the pasted runes one at
+// a time; ReadPaste collects them into a single string.
+func (rr *RuneReader) SetBracketedPasteMode(enabled bool) {
+	rr.state.bracketedPaste = enabled
+}
+
+// termOut returns the writer mode-toggle escape sequences (bracketed paste
+// on/off) are written to. Tests inject their own via state.out; everyone
+// else gets the real tty.
+func (rr *RuneReader) termOut() io.Writer {
+	if rr.state.out != nil {
+		return rr.state.out
+	}
+	return os.Stdout
+}
+
+// firstErr returns the first non-nil error, or nil if all are nil. It lets
+// SetTermMode/RestoreTermMode attempt every side effect they own even when
+// an earlier one fails, instead of bailing out and leaving the tty in a
+// mismatched state.
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // For reading runes we just want to disable echo.
 func (rr *RuneReader) SetTermMode() error {
-	var tCurr C.struct_termios
-	var tNew C.struct_termios
-	var err error
-
-	err = getTerminalMode(os.Stdin.Fd(), &tCurr)
+	tCurr, err := getTerminalMode(os.Stdin.Fd())
 	if err != nil {
 		return err
 	}
 
 	// Persist current settings before we alter them.
-	rr.state.term = tCurr
+	rr.state.term = *tCurr
 
-	// Make a copy of current settings and pass the copy to tweaking function.
-	tNew = tCurr
+	// Make a copy of current settings and tweak the copy in place.
+	tNew := *tCurr
+	alterTerminalMode(&tNew)
 
-	err = alterTerminalMode(os.Stdin.Fd(), &tNew)
-	if err != nil {
-		return err
+	// Always apply raw mode, even if the cosmetic bracketed-paste-on write
+	// below fails; the reverse order would let a write error skip raw mode
+	// entirely.
+	modeErr := setTerminalMode(os.Stdin.Fd(), &tNew)
+
+	var pasteErr error
+	if rr.state.bracketedPaste {
+		_, pasteErr = rr.termOut().Write([]byte("\x1b[?2004h"))
 	}
 
-	return nil
+	return firstErr(modeErr, pasteErr)
 }
 
 func (rr *RuneReader) RestoreTermMode() error {
-	if err := setTerminalMode(os.Stdin.Fd(), &rr.state.term); err != nil {
-		return err
+	// Always restore the saved termios, even if the cosmetic
+	// bracketed-paste-off write below fails; a broken stdout must never
+	// leave the tty stuck in raw/no-echo mode.
+	restoreErr := setTerminalMode(os.Stdin.Fd(), &rr.state.term)
+
+	var pasteErr error
+	if rr.state.bracketedPaste {
+		_, pasteErr = rr.termOut().Write([]byte("\x1b[?2004l"))
 	}
-	return nil
+
+	return firstErr(restoreErr, pasteErr)
+}
+
+// State holds a terminal's mode as saved by MakeRaw, so it can later be
+// restored with Restore.
+type State struct {
+	termios unix.Termios
+}
+
+// MakeRaw puts the terminal connected to fd into full raw mode - suitable
+// for a caller that embeds survey in a larger TUI and wants to drive the
+// tty itself between prompts - and returns the previous state so it can be
+// restored with Restore. Unlike SetTermMode, which only turns off echo,
+// canonical mode and signal generation for reading runes, MakeRaw clears
+// every flag needed for raw I/O, matching golang.org/x/term.MakeRaw.
+func MakeRaw(fd uintptr) (*State, error) {
+	tCurr, err := getTerminalMode(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := *tCurr
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+
+	if err := setTerminalMode(fd, &raw); err != nil {
+		return nil, err
+	}
+	return &State{termios: *tCurr}, nil
+}
+
+// Restore restores the terminal connected to fd to a state saved by MakeRaw.
+func Restore(fd uintptr, state *State) error {
+	return setTerminalMode(fd, &state.termios)
+}
+
+// SetInputFilter installs a hook that ReadRune applies to every rune before
+// returning it. The hook returns the rune to deliver and whether to keep
+// it; returning false translates the rune to IgnoreKey, letting callers
+// block keys (e.g. Ctrl-Z) or remap them without patching survey's prompts.
+func (rr *RuneReader) SetInputFilter(filter func(rune) (rune, bool)) {
+	rr.state.inputFilter = filter
 }
 
-// ReadRune Parse escape sequences such as ESC [ A for arrow keys.
+// readCSIParams reads the body of a CSI/SS3 sequence: a run of decimal
+// parameters separated by `;`, terminated by a final byte in the range
+// `@`-`~`. It always returns at least one parameter (0 if none were given),
+// so callers don't need to special-case the no-parameter form used by
+// plain arrow keys (`ESC [ A`).
+func (rr *RuneReader) readCSIParams() (params []int, final rune, err error) {
+	cur := 0
+	for {
+		r, _, rerr := rr.state.reader.ReadRune()
+		if rerr != nil {
+			return params, 0, rerr
+		}
+
+		switch {
+		case r >= '0' && r <= '9':
+			cur = cur*10 + int(r-'0')
+		case r == ';':
+			params = append(params, cur)
+			cur = 0
+		case r >= '@' && r <= '~':
+			params = append(params, cur)
+			return params, r, nil
+		default:
+			// Not a well-formed CSI sequence; stop consuming and let the
+			// caller treat it as unrecognized.
+			params = append(params, cur)
+			return params, r, nil
+		}
+	}
+}
+
+// modsFromParams extracts the modifier parameter from a CSI sequence, e.g.
+// the `5` (Ctrl) in `1;5C`. The modifier is always the last parameter and
+// is only present when there's more than one.
+func modsFromParams(params []int) Mods {
+	if len(params) < 2 {
+		return 0
+	}
+	p := params[len(params)-1]
+	if p <= 1 {
+		return 0
+	}
+	return Mods(p - 1)
+}
+
+// ReadRune parses escape sequences such as ESC [ A for arrow keys, ESC [
+// n ~ for Home/Insert/Delete/End/PageUp/PageDown and function keys, ESC O
+// x for the SS3-encoded function keys F1-F4, and the modifier parameter
+// carried by sequences like ESC [ 1 ; 5 C (Ctrl+Right). The key and any
+// modifiers held down for the most recently read rune are also available
+// via Key(). If an input filter was installed with SetInputFilter, it is
+// applied to the result before it's returned.
 // See https://vt100.net/docs/vt102-ug/appendixc.html
 func (rr *RuneReader) ReadRune() (rune, int, error) {
+	r, size, err := rr.readRune()
+	if err != nil || rr.state.inputFilter == nil {
+		return r, size, err
+	}
+
+	filtered, ok := rr.state.inputFilter(r)
+	if !ok {
+		filtered = IgnoreKey
+	}
+	rr.state.key.Key = filtered
+	return filtered, size, nil
+}
+
+func (rr *RuneReader) readRune() (rune, int, error) {
+	rr.state.key = ModifiedKey{}
+
 	r, size, err := rr.state.reader.ReadRune()
 	if err != nil {
 		return r, size, err
 	}
 
 	if r != KeyEscape {
+		rr.state.key = ModifiedKey{Key: r}
 		return r, size, err
 	}
 
 	if rr.state.reader.Buffered() == 0 {
 		// no more characters so must be `Esc` key
+		rr.state.key = ModifiedKey{Key: KeyEscape}
 		return KeyEscape, 1, nil
 	}
 
@@ -150,36 +382,53 @@ func (rr *RuneReader) ReadRune() (rune, int, error) {
 	if r != normalKeypad && r != applicationKeypad {
 		return r, size, fmt.Errorf("unexpected escape sequence from terminal: %q", []rune{KeyEscape, r})
 	}
-
 	keypad := r
 
-	r, size, err = rr.state.reader.ReadRune()
+	params, final, err := rr.readCSIParams()
 	if err != nil {
-		return r, size, err
+		return final, 1, err
 	}
 
-	switch r {
-	case 'A': // ESC [ A or ESC O A
-		return KeyArrowUp, 1, nil
-	case 'B': // ESC [ B or ESC O B
-		return KeyArrowDown, 1, nil
-	case 'C': // ESC [ C or ESC O C
-		return KeyArrowRight, 1, nil
-	case 'D': // ESC [ D or ESC O D
-		return KeyArrowLeft, 1, nil
-	case 'F': // ESC [ F or ESC O F
-		return SpecialKeyEnd, 1, nil
-	case 'H': // ESC [ H or ESC O H
-		return SpecialKeyHome, 1, nil
-	case '3': // ESC [ 3
-		if keypad == normalKeypad {
-			// discard the following '~' key from buffer
-			_, _ = rr.state.reader.Discard(1)
-			return SpecialKeyDelete, 1, nil
-		}
+	var key rune
+	var ok bool
+	switch {
+	case keypad == applicationKeypad:
+		key, ok = ss3Keys[final]
+	case final == '~':
+		key, ok = tildeKeys[params[0]]
+	default:
+		key, ok = csiLetterKeys[final]
+	}
+	if !ok {
+		return IgnoreKey, 1, nil
 	}
 
-	// discard the following '~' key from buffer
-	_, _ = rr.state.reader.Discard(1)
-	return IgnoreKey, 1, nil
+	mods := modsFromParams(params)
+	rr.state.key = ModifiedKey{Key: key, Mods: mods}
+	return key, 1, nil
+}
+
+// Key returns the key and any modifiers (Shift/Alt/Ctrl) read by the most
+// recent call to ReadRune.
+func (rr *RuneReader) Key() ModifiedKey {
+	return rr.state.key
+}
+
+// ReadPaste reads and returns the text of a bracketed paste block, consuming
+// bytes up to (and discarding) the terminating KeyPasteEnd marker. Call it
+// immediately after ReadRune returns KeyPasteStart, e.g. when pasting
+// multi-line text into an Input or Editor prompt, so that embedded newlines
+// are treated as literal text rather than submitting the answer.
+func (rr *RuneReader) ReadPaste() (string, error) {
+	var buf bytes.Buffer
+	for {
+		r, _, err := rr.ReadRune()
+		if err != nil {
+			return buf.String(), err
+		}
+		if r == KeyPasteEnd {
+			return buf.String(), nil
+		}
+		buf.WriteRune(r)
+	}
 }
@@ -0,0 +1,192 @@
+//go:build solaris || illumos
+// +build solaris illumos
+
+package terminal
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// fakeFileReader adapts a bytes.Reader to the FileReader interface so tests
+// can drive ReadRune without a real tty.
+type fakeFileReader struct {
+	*bytes.Reader
+}
+
+func (fakeFileReader) Fd() uintptr { return 0 }
+
+func newTestRuneReader(input string) *RuneReader {
+	return &RuneReader{state: newRuneReaderState(fakeFileReader{bytes.NewReader([]byte(input))})}
+}
+
+func TestReadRune_CSISequences(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantKey  rune
+		wantMods Mods
+	}{
+		{"plain arrow up", "\x1b[A", KeyArrowUp, 0},
+		{"ss3 arrow up", "\x1bOA", KeyArrowUp, 0},
+		{"delete", "\x1b[3~", SpecialKeyDelete, 0},
+		{"home tilde", "\x1b[1~", SpecialKeyHome, 0},
+		{"insert", "\x1b[2~", SpecialKeyInsert, 0},
+		{"page up", "\x1b[5~", SpecialKeyPageUp, 0},
+		{"page down", "\x1b[6~", SpecialKeyPageDown, 0},
+		{"function key via tilde", "\x1b[15~", KeyF5, 0},
+		{"function key via ss3", "\x1bOP", KeyF1, 0},
+		{"ctrl+right", "\x1b[1;5C", KeyArrowRight, ModCtrl},
+		{"shift+up", "\x1b[1;2A", KeyArrowUp, ModShift},
+		{"alt+left", "\x1b[1;3D", KeyArrowLeft, ModAlt},
+		{"modified delete", "\x1b[3;5~", SpecialKeyDelete, ModCtrl},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := newTestRuneReader(tt.input)
+
+			key, _, err := rr.ReadRune()
+			if err != nil {
+				t.Fatalf("ReadRune() error = %v", err)
+			}
+			if key != tt.wantKey {
+				t.Errorf("ReadRune() key = %U, want %U", key, tt.wantKey)
+			}
+			if mods := rr.Key().Mods; mods != tt.wantMods {
+				t.Errorf("Key().Mods = %v, want %v", mods, tt.wantMods)
+			}
+		})
+	}
+}
+
+func TestReadRune_UnrecognizedSequenceIsIgnored(t *testing.T) {
+	// ESC [ 99 ~ isn't in any of the lookup tables; it should be consumed
+	// wholesale and reported as IgnoreKey rather than leaking the '~' into
+	// the next ReadRune call.
+	rr := newTestRuneReader("\x1b[99~x")
+
+	key, _, err := rr.ReadRune()
+	if err != nil {
+		t.Fatalf("ReadRune() error = %v", err)
+	}
+	if key != IgnoreKey {
+		t.Errorf("ReadRune() key = %U, want IgnoreKey", key)
+	}
+
+	key, _, err = rr.ReadRune()
+	if err != nil {
+		t.Fatalf("ReadRune() error = %v", err)
+	}
+	if key != 'x' {
+		t.Errorf("ReadRune() key = %q, want 'x'", key)
+	}
+}
+
+func TestReadPaste(t *testing.T) {
+	rr := newTestRuneReader("\x1b[200~hello\nworld\x1b[201~x")
+
+	start, _, err := rr.ReadRune()
+	if err != nil {
+		t.Fatalf("ReadRune() error = %v", err)
+	}
+	if start != KeyPasteStart {
+		t.Fatalf("ReadRune() key = %U, want KeyPasteStart", start)
+	}
+
+	got, err := rr.ReadPaste()
+	if err != nil {
+		t.Fatalf("ReadPaste() error = %v", err)
+	}
+	if want := "hello\nworld"; got != want {
+		t.Errorf("ReadPaste() = %q, want %q", got, want)
+	}
+
+	// The byte following the paste framing should read normally.
+	key, _, err := rr.ReadRune()
+	if err != nil {
+		t.Fatalf("ReadRune() error = %v", err)
+	}
+	if key != 'x' {
+		t.Errorf("ReadRune() key = %q, want 'x'", key)
+	}
+}
+
+func TestBracketedPasteModeToggle(t *testing.T) {
+	rr := newTestRuneReader("")
+	rr.SetBracketedPasteMode(true)
+
+	var out bytes.Buffer
+	rr.state.out = &out
+
+	if err := rr.SetTermMode(); err != nil {
+		t.Skipf("SetTermMode() unavailable in this environment: %v", err)
+	}
+	if got, want := out.String(), "\x1b[?2004h"; got != want {
+		t.Errorf("SetTermMode() wrote %q, want %q", got, want)
+	}
+
+	out.Reset()
+	if err := rr.RestoreTermMode(); err != nil {
+		t.Fatalf("RestoreTermMode() error = %v", err)
+	}
+	if got, want := out.String(), "\x1b[?2004l"; got != want {
+		t.Errorf("RestoreTermMode() wrote %q, want %q", got, want)
+	}
+}
+
+func TestSetInputFilter(t *testing.T) {
+	rr := newTestRuneReader("qab")
+	rr.SetInputFilter(func(r rune) (rune, bool) {
+		switch r {
+		case 'q':
+			return r, false // blocked
+		case 'a':
+			return 'b', true // remapped
+		default:
+			return r, true
+		}
+	})
+
+	key, _, err := rr.ReadRune()
+	if err != nil {
+		t.Fatalf("ReadRune() error = %v", err)
+	}
+	if key != IgnoreKey {
+		t.Errorf("ReadRune() key = %q, want IgnoreKey for blocked 'q'", key)
+	}
+
+	key, _, err = rr.ReadRune()
+	if err != nil {
+		t.Fatalf("ReadRune() error = %v", err)
+	}
+	if key != 'b' {
+		t.Errorf("ReadRune() key = %q, want 'b' (remapped from 'a')", key)
+	}
+
+	key, _, err = rr.ReadRune()
+	if err != nil {
+		t.Fatalf("ReadRune() error = %v", err)
+	}
+	if key != 'b' {
+		t.Errorf("ReadRune() key = %q, want 'b'", key)
+	}
+}
+
+func TestMakeRawAndRestore(t *testing.T) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		t.Skipf("no controlling tty available: %v", err)
+	}
+	defer tty.Close()
+
+	fd := tty.Fd()
+	state, err := MakeRaw(fd)
+	if err != nil {
+		t.Fatalf("MakeRaw() error = %v", err)
+	}
+	if err := Restore(fd, state); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+}